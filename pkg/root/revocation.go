@@ -0,0 +1,278 @@
+package root
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// RevocationStatus is the result of checking a single certificate against a
+// revocation source.
+type RevocationStatus int
+
+const (
+	// RevocationUnknown means the checker could not determine whether the
+	// certificate has been revoked, e.g. because no CRL/OCSP responder was
+	// reachable or the certificate carries no revocation extensions.
+	RevocationUnknown RevocationStatus = iota
+	// RevocationOK means the certificate was checked against a revocation
+	// source and found not to be revoked.
+	RevocationOK
+	// RevocationRevoked means the certificate has been revoked.
+	RevocationRevoked
+)
+
+func (s RevocationStatus) String() string {
+	switch s {
+	case RevocationOK:
+		return "ok"
+	case RevocationRevoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// RevocationPolicy controls how a RevocationUnknown result affects
+// verification.
+type RevocationPolicy int
+
+const (
+	// RevocationSoftFail treats RevocationUnknown as acceptable; only a
+	// confirmed RevocationRevoked fails verification. This is the default.
+	RevocationSoftFail RevocationPolicy = iota
+	// RevocationHardFail treats RevocationUnknown the same as
+	// RevocationRevoked.
+	RevocationHardFail
+)
+
+// RevocationCache lets CRLs and OCSP responses be reused across checks
+// instead of being fetched on every call. Implementations must be safe for
+// concurrent use.
+type RevocationCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// RevocationChecker determines whether a certificate has been revoked by
+// its issuer, as of a given point in time. Implementations are expected to
+// honor the CRL Distribution Points and Authority Information Access
+// extensions embedded in cert.
+type RevocationChecker interface {
+	// CheckCertificate returns the revocation status of cert, issued by
+	// issuer, as known at the given time.
+	CheckCertificate(cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error)
+}
+
+// HTTPClient is the subset of http.Client used to fetch CRLs and OCSP
+// responses, satisfied by http.DefaultClient and test doubles alike.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// CRLChecker checks certificates against the CRL Distribution Points they
+// embed.
+type CRLChecker struct {
+	Client HTTPClient
+	Cache  RevocationCache
+}
+
+// NewCRLChecker returns a CRLChecker using http.DefaultClient and no cache.
+func NewCRLChecker() *CRLChecker {
+	return &CRLChecker{Client: http.DefaultClient}
+}
+
+func (c *CRLChecker) CheckCertificate(cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error) {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return RevocationUnknown, nil
+	}
+
+	var lastErr error
+	for _, url := range cert.CRLDistributionPoints {
+		crl, err := c.fetchCRL(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			lastErr = fmt.Errorf("CRL from %s is not signed by %s: %w", url, issuer.Subject, err)
+			continue
+		}
+		if crl.NextUpdate.IsZero() || crl.NextUpdate.Before(at) {
+			lastErr = fmt.Errorf("CRL from %s is stale: NextUpdate %s is before %s", url, crl.NextUpdate, at)
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 && !revoked.RevocationTime.After(at) {
+				return RevocationRevoked, nil
+			}
+		}
+		return RevocationOK, nil
+	}
+	return RevocationUnknown, lastErr
+}
+
+func (c *CRLChecker) fetchCRL(url string) (*x509.RevocationList, error) {
+	if c.Cache != nil {
+		if cached, ok := c.Cache.Get(url); ok {
+			return x509.ParseRevocationList(cached)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching CRL from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching CRL from %s: unexpected status %d", url, resp.StatusCode)
+	}
+
+	body := make([]byte, 0, 4096)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		body = append(body, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	if c.Cache != nil {
+		c.Cache.Set(url, body)
+	}
+	return x509.ParseRevocationList(body)
+}
+
+// OCSPChecker checks certificates against the OCSP responder named in their
+// Authority Information Access extension. Request construction and response
+// parsing are left to the caller-supplied RequestFunc so this type has no
+// hard dependency on a particular OCSP library; NewOCSPChecker wires up a
+// default RequestFunc backed by golang.org/x/crypto/ocsp.
+type OCSPChecker struct {
+	Client HTTPClient
+	Cache  RevocationCache
+
+	// RequestFunc builds and sends the OCSP request for cert/issuer against
+	// responderURL, returning the parsed revocation status as of at.
+	RequestFunc func(client HTTPClient, cache RevocationCache, responderURL string, cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error)
+}
+
+// NewOCSPChecker returns an OCSPChecker using http.DefaultClient, no cache,
+// and defaultOCSPRequest as its RequestFunc.
+func NewOCSPChecker() *OCSPChecker {
+	return &OCSPChecker{Client: http.DefaultClient, RequestFunc: defaultOCSPRequest}
+}
+
+func (c *OCSPChecker) CheckCertificate(cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error) {
+	if len(cert.OCSPServer) == 0 || c.RequestFunc == nil {
+		return RevocationUnknown, nil
+	}
+
+	var lastErr error
+	for _, responderURL := range cert.OCSPServer {
+		status, err := c.RequestFunc(c.Client, c.Cache, responderURL, cert, issuer, at)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return status, nil
+	}
+	return RevocationUnknown, lastErr
+}
+
+// defaultOCSPRequest is OCSPChecker's default RequestFunc: it builds an
+// OCSP request via golang.org/x/crypto/ocsp, POSTs it to responderURL (using
+// cache, keyed by responder URL and serial number, to reuse an earlier
+// response instead of re-requesting it), and maps the parsed response to a
+// RevocationStatus as of at. A cached response is reused regardless of its
+// NextUpdate: once it is stale the result is RevocationUnknown, and no new
+// request is made.
+func defaultOCSPRequest(client HTTPClient, cache RevocationCache, responderURL string, cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error) {
+	reqBytes, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return RevocationUnknown, fmt.Errorf("creating OCSP request for %s: %w", responderURL, err)
+	}
+
+	cacheKey := responderURL + ":" + cert.SerialNumber.String()
+	var respBytes []byte
+	if cache != nil {
+		if cached, ok := cache.Get(cacheKey); ok {
+			respBytes = cached
+		}
+	}
+	if respBytes == nil {
+		req, err := http.NewRequest(http.MethodPost, responderURL, bytes.NewReader(reqBytes))
+		if err != nil {
+			return RevocationUnknown, err
+		}
+		req.Header.Set("Content-Type", "application/ocsp-request")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return RevocationUnknown, fmt.Errorf("requesting OCSP response from %s: %w", responderURL, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return RevocationUnknown, fmt.Errorf("requesting OCSP response from %s: unexpected status %d", responderURL, resp.StatusCode)
+		}
+
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(resp.Body); err != nil {
+			return RevocationUnknown, fmt.Errorf("reading OCSP response from %s: %w", responderURL, err)
+		}
+		respBytes = body.Bytes()
+		if cache != nil {
+			cache.Set(cacheKey, respBytes)
+		}
+	}
+
+	ocspResp, err := ocsp.ParseResponseForCert(respBytes, cert, issuer)
+	if err != nil {
+		return RevocationUnknown, fmt.Errorf("parsing OCSP response from %s: %w", responderURL, err)
+	}
+	if !ocspResp.NextUpdate.IsZero() && ocspResp.NextUpdate.Before(at) {
+		return RevocationUnknown, fmt.Errorf("OCSP response from %s is stale: NextUpdate %s is before %s", responderURL, ocspResp.NextUpdate, at)
+	}
+
+	switch ocspResp.Status {
+	case ocsp.Good:
+		return RevocationOK, nil
+	case ocsp.Revoked:
+		if !ocspResp.RevokedAt.After(at) {
+			return RevocationRevoked, nil
+		}
+		return RevocationOK, nil
+	default:
+		return RevocationUnknown, nil
+	}
+}
+
+// CompositeRevocationChecker consults each checker in order and returns the
+// first status more specific than RevocationUnknown.
+type CompositeRevocationChecker struct {
+	Checkers []RevocationChecker
+}
+
+func (c *CompositeRevocationChecker) CheckCertificate(cert, issuer *x509.Certificate, at time.Time) (RevocationStatus, error) {
+	var lastErr error
+	for _, checker := range c.Checkers {
+		status, err := checker.CheckCertificate(cert, issuer, at)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if status != RevocationUnknown {
+			return status, nil
+		}
+	}
+	return RevocationUnknown, lastErr
+}
@@ -0,0 +1,264 @@
+package root
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func generateTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing CA certificate: %v", err)
+	}
+	return cert, key
+}
+
+func generateTestLeaf(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, serial int64, crlURL string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating leaf key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "test-tsa-leaf"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		CRLDistributionPoints: []string{crlURL},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating leaf certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing leaf certificate: %v", err)
+	}
+	return cert
+}
+
+func newCRLServer(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, revoked []x509.RevocationListEntry) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := &x509.RevocationList{
+			Number:                    big.NewInt(1),
+			ThisUpdate:                time.Now().Add(-time.Minute),
+			NextUpdate:                time.Now().Add(time.Hour),
+			RevokedCertificateEntries: revoked,
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+		if err != nil {
+			t.Fatalf("creating CRL: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(der)
+	}))
+}
+
+func TestCRLCheckerDetectsRevokedCertificate(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+
+	// The CRL distribution point must be known before the leaf is minted,
+	// but the CRL itself must name the leaf's serial number -- so the
+	// handler reads revokedSerial, which is set from the leaf once it has
+	// been generated.
+	var revokedSerial *big.Int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var revoked []x509.RevocationListEntry
+		if revokedSerial != nil {
+			revoked = []x509.RevocationListEntry{
+				{SerialNumber: revokedSerial, RevocationTime: time.Now().Add(-time.Minute)},
+			}
+		}
+		template := &x509.RevocationList{
+			Number:                    big.NewInt(1),
+			ThisUpdate:                time.Now().Add(-time.Minute),
+			NextUpdate:                time.Now().Add(time.Hour),
+			RevokedCertificateEntries: revoked,
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+		if err != nil {
+			t.Fatalf("creating CRL: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(der)
+	}))
+	defer server.Close()
+
+	leaf := generateTestLeaf(t, ca, caKey, 42, server.URL)
+	revokedSerial = leaf.SerialNumber
+
+	checker := NewCRLChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != RevocationRevoked {
+		t.Fatalf("expected RevocationRevoked, got %s", status)
+	}
+}
+
+func TestCRLCheckerAcceptsNonRevokedCertificate(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+
+	var crlURL string
+	server := newCRLServer(t, ca, caKey, nil)
+	defer server.Close()
+	crlURL = server.URL
+
+	leaf := generateTestLeaf(t, ca, caKey, 7, crlURL)
+
+	checker := NewCRLChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != RevocationOK {
+		t.Fatalf("expected RevocationOK, got %s", status)
+	}
+}
+
+func TestCRLCheckerRejectsStaleCRL(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: time.Now().Add(-2 * time.Hour),
+			NextUpdate: time.Now().Add(-time.Hour),
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+		if err != nil {
+			t.Fatalf("creating CRL: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(der)
+	}))
+	defer server.Close()
+
+	leaf := generateTestLeaf(t, ca, caKey, 11, server.URL)
+
+	checker := NewCRLChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if status != RevocationUnknown {
+		t.Fatalf("expected RevocationUnknown for a stale CRL, got %s", status)
+	}
+	if err == nil {
+		t.Fatal("expected an error explaining why the CRL was rejected")
+	}
+}
+
+func newOCSPServer(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, status int, revokedAt time.Time) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := ocsp.Response{
+			Status:       status,
+			SerialNumber: nil, // set per-request below
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    revokedAt,
+		}
+		req, err := ocsp.ParseRequest(mustReadBody(t, r))
+		if err != nil {
+			t.Fatalf("parsing OCSP request: %v", err)
+		}
+		template.SerialNumber = req.SerialNumber
+
+		der, err := ocsp.CreateResponse(ca, ca, template, caKey)
+		if err != nil {
+			t.Fatalf("creating OCSP response: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(der)
+	}))
+}
+
+func mustReadBody(t *testing.T, r *http.Request) []byte {
+	t.Helper()
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		t.Fatalf("reading OCSP request body: %v", err)
+	}
+	return body.Bytes()
+}
+
+func TestOCSPCheckerAcceptsGoodCertificate(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+	server := newOCSPServer(t, ca, caKey, ocsp.Good, time.Time{})
+	defer server.Close()
+
+	leaf := generateTestLeaf(t, ca, caKey, 21, "")
+	leaf.OCSPServer = []string{server.URL}
+
+	checker := NewOCSPChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != RevocationOK {
+		t.Fatalf("expected RevocationOK, got %s", status)
+	}
+}
+
+func TestOCSPCheckerDetectsRevokedCertificate(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+	server := newOCSPServer(t, ca, caKey, ocsp.Revoked, time.Now().Add(-time.Minute))
+	defer server.Close()
+
+	leaf := generateTestLeaf(t, ca, caKey, 22, "")
+	leaf.OCSPServer = []string{server.URL}
+
+	checker := NewOCSPChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != RevocationRevoked {
+		t.Fatalf("expected RevocationRevoked, got %s", status)
+	}
+}
+
+func TestCRLCheckerReturnsUnknownWithoutDistributionPoints(t *testing.T) {
+	ca, caKey := generateTestCA(t)
+	leaf := generateTestLeaf(t, ca, caKey, 9, "")
+	leaf.CRLDistributionPoints = nil
+
+	checker := NewCRLChecker()
+	status, err := checker.CheckCertificate(leaf, ca, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != RevocationUnknown {
+		t.Fatalf("expected RevocationUnknown, got %s", status)
+	}
+}
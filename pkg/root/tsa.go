@@ -0,0 +1,12 @@
+package root
+
+// TimestampAuthority describes a single RFC 3161 timestamp authority: where
+// to send requests, and the certificate authority trusted material should
+// use to verify the tokens it returns.
+type TimestampAuthority struct {
+	// URL is the TSA's RFC 3161 HTTP endpoint.
+	URL string
+	// CertificateAuthority is the CA chain (leaf/intermediates/root) that
+	// issued the TSA's signing certificate.
+	CertificateAuthority *CertificateAuthority
+}
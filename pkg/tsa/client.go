@@ -0,0 +1,174 @@
+// Package tsa requests RFC 3161 timestamp tokens from timestamp
+// authorities, as a counterpart to the verification done in
+// pkg/verifier.TimestampAuthorityVerifier.
+package tsa
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/digitorus/timestamp"
+
+	"github.com/github/sigstore-verifier/pkg/root"
+)
+
+// TimestampRequester produces an RFC 3161 timestamp token over a signature.
+//
+// Implementations must send exactly the raw signature bytes that
+// verifier.verifySignedTimestamp re-hashes when validating the resulting
+// token, never a whole DSSE envelope, so that tokens requested here verify
+// against bundles built from them.
+type TimestampRequester interface {
+	// RequestTimestamp returns a DER-encoded RFC 3161 timestamp token over
+	// signatureBytes, obtained from tsa.
+	RequestTimestamp(ctx context.Context, signatureBytes []byte, tsa root.TimestampAuthority) ([]byte, error)
+}
+
+// HTTPTimestampRequester requests timestamps from RFC 3161-compliant HTTP
+// timestamp authorities.
+type HTTPTimestampRequester struct {
+	Client     *http.Client
+	HashAlg    crypto.Hash
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// NewHTTPTimestampRequester returns an HTTPTimestampRequester with sensible
+// defaults: http.DefaultClient, SHA-256 message imprints, and up to 3
+// retries with 500ms exponential backoff.
+func NewHTTPTimestampRequester() *HTTPTimestampRequester {
+	return &HTTPTimestampRequester{
+		Client:     http.DefaultClient,
+		HashAlg:    crypto.SHA256,
+		MaxRetries: 3,
+		RetryDelay: 500 * time.Millisecond,
+	}
+}
+
+func (r *HTTPTimestampRequester) RequestTimestamp(ctx context.Context, signatureBytes []byte, tsa root.TimestampAuthority) ([]byte, error) {
+	reqBytes, err := timestamp.CreateRequest(bytes.NewReader(signatureBytes), &timestamp.RequestOptions{
+		Hash:         r.HashAlg,
+		Certificates: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating timestamp request: %w", err)
+	}
+
+	var lastErr error
+	delay := r.RetryDelay
+	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		token, err := r.doRequest(ctx, reqBytes, tsa.URL)
+		if err == nil {
+			return token, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("requesting timestamp from %s after %d attempts: %w", tsa.URL, r.MaxRetries+1, lastErr)
+}
+
+func (r *HTTPTimestampRequester) doRequest(ctx context.Context, reqBytes []byte, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(resp.Body); err != nil {
+		return nil, fmt.Errorf("reading timestamp response from %s: %w", url, err)
+	}
+	return body.Bytes(), nil
+}
+
+// RequestTimestamps requests one token from each of tsas in parallel using
+// requester, returning the tokens obtained successfully. It only errors if
+// every request failed, so callers that configured a threshold lower than
+// len(tsas) can tolerate some of them being unreachable.
+func RequestTimestamps(ctx context.Context, requester TimestampRequester, signatureBytes []byte, tsas []root.TimestampAuthority) ([][]byte, error) {
+	results := make([][]byte, len(tsas))
+	errs := make([]error, len(tsas))
+
+	var wg sync.WaitGroup
+	for i, authority := range tsas {
+		wg.Add(1)
+		go func(i int, authority root.TimestampAuthority) {
+			defer wg.Done()
+			token, err := requester.RequestTimestamp(ctx, signatureBytes, authority)
+			results[i], errs[i] = token, err
+		}(i, authority)
+	}
+	wg.Wait()
+
+	tokens := make([][]byte, 0, len(tsas))
+	var failures []error
+	for i, token := range results {
+		if errs[i] != nil {
+			failures = append(failures, errs[i])
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("all %d timestamp requests failed: %v", len(tsas), failures)
+	}
+	return tokens, nil
+}
+
+// BundleTimestampAttacher is satisfied by a bundle under construction that
+// can accept additional RFC 3161 timestamp tokens, e.g. bundle.Bundle. It
+// lets RequestAndAttachTimestamps wire requested tokens straight onto the
+// bundle being built instead of handing raw tokens back to the caller to
+// attach themselves.
+type BundleTimestampAttacher interface {
+	AddTimestamp(token []byte) error
+}
+
+// RequestAndAttachTimestamps requests a timestamp from each of tsas via
+// RequestTimestamps and attaches every token obtained to bundleBuilder. It
+// errors if fewer than threshold tokens end up attached, since that's the
+// same bar TimestampAuthorityVerifier.threshold will later require the
+// bundle to meet.
+func RequestAndAttachTimestamps(ctx context.Context, requester TimestampRequester, signatureBytes []byte, tsas []root.TimestampAuthority, bundleBuilder BundleTimestampAttacher, threshold int) error {
+	tokens, err := RequestTimestamps(ctx, requester, signatureBytes, tsas)
+	if err != nil {
+		return err
+	}
+
+	var attachErrs []error
+	attached := 0
+	for _, token := range tokens {
+		if err := bundleBuilder.AddTimestamp(token); err != nil {
+			attachErrs = append(attachErrs, err)
+			continue
+		}
+		attached++
+	}
+	if attached < threshold {
+		return fmt.Errorf("attached %d of %d required timestamps to the bundle: %v", attached, threshold, attachErrs)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package tsa
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/github/sigstore-verifier/pkg/root"
+)
+
+type fakeTimestampRequester struct {
+	tokens map[string][]byte
+	errs   map[string]error
+}
+
+func (f *fakeTimestampRequester) RequestTimestamp(_ context.Context, _ []byte, tsa root.TimestampAuthority) ([]byte, error) {
+	if err, ok := f.errs[tsa.URL]; ok {
+		return nil, err
+	}
+	return f.tokens[tsa.URL], nil
+}
+
+type fakeBundleBuilder struct {
+	attached  [][]byte
+	rejectAt  int
+	rejectAll bool
+}
+
+func (f *fakeBundleBuilder) AddTimestamp(token []byte) error {
+	if f.rejectAll || (f.rejectAt > 0 && len(f.attached) == f.rejectAt) {
+		return errors.New("bundle refused timestamp")
+	}
+	f.attached = append(f.attached, token)
+	return nil
+}
+
+func TestRequestAndAttachTimestampsAttachesEveryTokenObtained(t *testing.T) {
+	tsas := []root.TimestampAuthority{{URL: "tsa-a"}, {URL: "tsa-b"}}
+	requester := &fakeTimestampRequester{tokens: map[string][]byte{
+		"tsa-a": []byte("token-a"),
+		"tsa-b": []byte("token-b"),
+	}}
+	builder := &fakeBundleBuilder{}
+
+	if err := RequestAndAttachTimestamps(context.Background(), requester, []byte("sig"), tsas, builder, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(builder.attached) != 2 {
+		t.Fatalf("expected 2 tokens attached, got %d", len(builder.attached))
+	}
+}
+
+func TestRequestAndAttachTimestampsErrorsBelowThreshold(t *testing.T) {
+	tsas := []root.TimestampAuthority{{URL: "tsa-a"}, {URL: "tsa-b"}}
+	requester := &fakeTimestampRequester{
+		tokens: map[string][]byte{"tsa-a": []byte("token-a")},
+		errs:   map[string]error{"tsa-b": errors.New("unreachable")},
+	}
+	builder := &fakeBundleBuilder{}
+
+	if err := RequestAndAttachTimestamps(context.Background(), requester, []byte("sig"), tsas, builder, 2); err == nil {
+		t.Fatal("expected an error: only 1 of 2 required timestamps could be attached")
+	}
+}
+
+func TestRequestAndAttachTimestampsErrorsWhenBundleRejectsToken(t *testing.T) {
+	tsas := []root.TimestampAuthority{{URL: "tsa-a"}}
+	requester := &fakeTimestampRequester{tokens: map[string][]byte{"tsa-a": []byte("token-a")}}
+	builder := &fakeBundleBuilder{rejectAll: true}
+
+	if err := RequestAndAttachTimestamps(context.Background(), requester, []byte("sig"), tsas, builder, 1); err == nil {
+		t.Fatal("expected an error when the bundle refuses every token")
+	}
+}
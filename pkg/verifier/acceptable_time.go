@@ -0,0 +1,89 @@
+package verifier
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github/sigstore-verifier/pkg/bundle"
+	"github.com/github/sigstore-verifier/pkg/root"
+)
+
+// TimeSource identifies where an AcceptableTime was obtained from.
+type TimeSource int
+
+const (
+	// TSATimeSource is a time attested by a timestamp authority.
+	TSATimeSource TimeSource = iota
+	// RekorTimeSource is a transparency log's IntegratedTime.
+	RekorTimeSource
+	// ObserverTimeSource is a time supplied by the verifier's caller
+	// rather than derived from the bundle itself, e.g. "now".
+	ObserverTimeSource
+)
+
+// AcceptableTime is a point in time, backed by a chain of trust, that a
+// verifier is willing to treat as the signing time when checking a signing
+// certificate's validity window.
+type AcceptableTime struct {
+	Source TimeSource
+	Time   time.Time
+	// Chain is the certificate chain that vouches for Time, e.g. a TSA's
+	// leaf, intermediates and root. It may be nil for sources, like Rekor, that
+	// aren't backed by a certificate chain of their own.
+	Chain []*x509.Certificate
+}
+
+// VerifyAcceptableTimes checks each of times against the signing
+// certificate's validity window via verificationContent.ValidAtTime, then
+// applies threshold rules across sources via selectAcceptableTimes.
+//
+// This replaces what used to be two separate checks: verifySignedTimestamp
+// validating the leaf against each TSA's own validity window, and a
+// ValidAtTime check repeated once per timestamp source. Collecting every
+// source's AcceptableTime first and evaluating them together here makes it
+// explicit whether, say, a single Rekor IntegratedTime is sufficient on its
+// own when no TSA is configured, and lets tests substitute a fake time
+// source instead of a real certificate chain.
+func VerifyAcceptableTimes(times []AcceptableTime, verificationContent bundle.VerificationContent, trustedMaterial root.TrustedMaterial, tsaThreshold int) ([]AcceptableTime, error) {
+	valid := make([]AcceptableTime, 0, len(times))
+	for _, t := range times {
+		if verificationContent.ValidAtTime(t.Time, trustedMaterial) {
+			valid = append(valid, t)
+		}
+	}
+	return selectAcceptableTimes(valid, tsaThreshold)
+}
+
+// selectAcceptableTimes applies threshold rules, across sources, to times
+// that have already been confirmed to fall within the signing certificate's
+// validity window.
+//
+// TSA-sourced times must meet tsaThreshold on their own -- this mirrors
+// TimestampAuthorityVerifier.threshold's requirement that a minimum number
+// of independent TSAs agree, and a non-TSA source shouldn't be able to
+// satisfy that agreement requirement on a TSA's behalf. When tsaThreshold
+// is 0 (no TSA configured), a single acceptable time from any source is
+// sufficient: with nothing to independently corroborate, e.g. a lone Rekor
+// IntegratedTime, there's no agreement threshold left to apply.
+func selectAcceptableTimes(valid []AcceptableTime, tsaThreshold int) ([]AcceptableTime, error) {
+	var tsaTimes []AcceptableTime
+	for _, t := range valid {
+		if t.Source == TSATimeSource {
+			tsaTimes = append(tsaTimes, t)
+		}
+	}
+
+	if tsaThreshold > 0 {
+		if len(tsaTimes) < tsaThreshold {
+			return nil, fmt.Errorf("only %d of %d required TSA-backed acceptable times fall within the signing certificate's validity window", len(tsaTimes), tsaThreshold)
+		}
+		return tsaTimes, nil
+	}
+
+	if len(valid) == 0 {
+		return nil, errors.New("no acceptable time falls within the signing certificate's validity window")
+	}
+	return valid, nil
+}
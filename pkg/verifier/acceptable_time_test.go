@@ -0,0 +1,50 @@
+package verifier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectAcceptableTimesRequiresTSAThresholdAmongTSATimesOnly(t *testing.T) {
+	now := time.Now()
+	valid := []AcceptableTime{
+		{Source: TSATimeSource, Time: now},
+		{Source: RekorTimeSource, Time: now},
+	}
+
+	if _, err := selectAcceptableTimes(valid, 2); err == nil {
+		t.Fatal("expected an error: only 1 TSA time is present but 2 are required")
+	}
+
+	valid = append(valid, AcceptableTime{Source: TSATimeSource, Time: now})
+	accepted, err := selectAcceptableTimes(valid, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(accepted) != 2 {
+		t.Fatalf("expected 2 accepted TSA times, got %d", len(accepted))
+	}
+	for _, a := range accepted {
+		if a.Source != TSATimeSource {
+			t.Fatalf("expected only TSA-sourced times to be returned, got %v", a.Source)
+		}
+	}
+}
+
+func TestSelectAcceptableTimesAllowsLoneRekorTimeWithoutTSA(t *testing.T) {
+	valid := []AcceptableTime{{Source: RekorTimeSource, Time: time.Now()}}
+
+	accepted, err := selectAcceptableTimes(valid, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(accepted) != 1 {
+		t.Fatalf("expected the lone Rekor time to be accepted, got %d", len(accepted))
+	}
+}
+
+func TestSelectAcceptableTimesRejectsEmptyInput(t *testing.T) {
+	if _, err := selectAcceptableTimes(nil, 0); err == nil {
+		t.Fatal("expected an error when no acceptable time is available")
+	}
+}
@@ -2,20 +2,49 @@ package verifier
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
+	"github.com/digitorus/timestamp"
 	tsaverification "github.com/sigstore/timestamp-authority/pkg/verification"
 
-	"github.com/github/sigstore-verifier/pkg/bundle"
 	"github.com/github/sigstore-verifier/pkg/root"
 )
 
 type TimestampAuthorityVerifier struct {
-	trustedMaterial root.TrustedMaterial
-	threshold       int
+	trustedMaterial          root.TrustedMaterial
+	threshold                int
+	revocationChecker        root.RevocationChecker
+	revocationPolicy         root.RevocationPolicy
+	allowedTSAHashAlgorithms []crypto.Hash
+}
+
+// WithAllowedTSAHashAlgorithms restricts the message imprint hash
+// algorithms p will accept from a TSA. TSTInfo.MessageImprint carries its
+// own hashAlgorithm OID, so without this a TSA configured for a weak
+// algorithm (e.g. SHA-1) would still verify. An empty/unset list allows
+// any algorithm the timestamp-authority library can parse, matching prior
+// behavior.
+func (p *TimestampAuthorityVerifier) WithAllowedTSAHashAlgorithms(algorithms []crypto.Hash) *TimestampAuthorityVerifier {
+	p.allowedTSAHashAlgorithms = algorithms
+	return p
+}
+
+// WithRevocationChecker configures p to consult checker for every
+// certificate in a TSA's chain (leaf and intermediates) before trusting one
+// of its timestamps. policy controls whether an inconclusive result is
+// treated as acceptable (root.RevocationSoftFail) or as revoked
+// (root.RevocationHardFail).
+func (p *TimestampAuthorityVerifier) WithRevocationChecker(checker root.RevocationChecker, policy root.RevocationPolicy) *TimestampAuthorityVerifier {
+	p.revocationChecker = checker
+	p.revocationPolicy = policy
+	return p
 }
 
 func (p *TimestampAuthorityVerifier) Verify(entity SignedEntity) error {
@@ -23,14 +52,186 @@ func (p *TimestampAuthorityVerifier) Verify(entity SignedEntity) error {
 	return err
 }
 
-func (p *TimestampAuthorityVerifier) NewVerify(entity SignedEntity) ([]time.Time, error) {
-	signedTimestamps, err := entity.Timestamps()
-	// TODO: dedupe signed timestamps, since these can be maliciously repeated
+// verifiedTimes extracts the verified times from a slice of AcceptableTime,
+// preserving NewVerify's historical []time.Time return type.
+func verifiedTimes(times []AcceptableTime) []time.Time {
+	out := make([]time.Time, len(times))
+	for i, t := range times {
+		out[i] = t.Time
+	}
+	return out
+}
+
+// VerifyOption customizes the behavior of NewVerify.
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	nonce           []byte
+	acceptableTimes []AcceptableTime
+}
+
+// WithNonce requires every signed timestamp's TSTInfo.nonce field to equal
+// nonce, rejecting any response that doesn't match. This closes the gap
+// where a stale TSA response (captured for a different request) is reused
+// across signatures.
+func WithNonce(nonce []byte) VerifyOption {
+	return func(o *verifyOptions) {
+		o.nonce = nonce
+	}
+}
+
+// WithAcceptableTimes feeds additional AcceptableTimes -- e.g. a Rekor
+// entry's IntegratedTime, or an observer-supplied time -- into the pool
+// NewVerify evaluates alongside the times it verifies from TSA responses.
+// This is how non-TSA sources join the threshold decision made by
+// VerifyAcceptableTimes.
+func WithAcceptableTimes(times ...AcceptableTime) VerifyOption {
+	return func(o *verifyOptions) {
+		o.acceptableTimes = append(o.acceptableTimes, times...)
+	}
+}
+
+// TimestampRejectionError reports the signed timestamps that NewVerify
+// discarded before evaluating the threshold, because they did not parse as
+// a timestamp response, duplicated an earlier response, or carried a nonce
+// that didn't match the one supplied via WithNonce.
+type TimestampRejectionError struct {
+	MalformedIndexes     []int
+	DuplicateIndexes     []int
+	NonceMismatchIndexes []int
+}
+
+func (e *TimestampRejectionError) Error() string {
+	return fmt.Sprintf("rejected signed timestamps: %d malformed %v, %d duplicate(s) %v, %d nonce mismatch(es) %v",
+		len(e.MalformedIndexes), e.MalformedIndexes,
+		len(e.DuplicateIndexes), e.DuplicateIndexes,
+		len(e.NonceMismatchIndexes), e.NonceMismatchIndexes)
+}
+
+func (e *TimestampRejectionError) hasRejections() bool {
+	return len(e.MalformedIndexes) > 0 || len(e.DuplicateIndexes) > 0 || len(e.NonceMismatchIndexes) > 0
+}
+
+// RevocationError reports that a signed timestamp was otherwise verifiable
+// but had to be rejected because a certificate in its TSA's chain is
+// revoked, or its revocation status is unknown under root.RevocationHardFail.
+// NewVerify surfaces this distinctly from the generic "unable to verify
+// timestamp" error so callers can tell a revoked TSA apart from a malformed
+// or untrusted one.
+type RevocationError struct {
+	err error
+}
+
+func (e *RevocationError) Error() string {
+	return fmt.Sprintf("signed timestamp rejected: %s", e.err)
+}
+
+func (e *RevocationError) Unwrap() error {
+	return e.err
+}
+
+// dedupeSignedTimestamps parses each raw signed timestamp, drops any that
+// don't parse as a TSTInfo, drops any whose TSTInfo duplicates one seen
+// earlier in the list, and (when nonce is non-empty) drops any whose
+// TSTInfo.nonce doesn't match it. A signer can otherwise satisfy
+// threshold=N by copying the same TSA response N times, or by replaying a
+// stale response captured for an earlier nonce -- and an unparseable token
+// must be rejected here too, or it would silently skip nonce enforcement.
+func dedupeSignedTimestamps(signedTimestamps [][]byte, nonce []byte) ([][]byte, *TimestampRejectionError) {
+	rejections := &TimestampRejectionError{}
+	parsed := make([]*timestamp.Timestamp, len(signedTimestamps))
+	for i, raw := range signedTimestamps {
+		ts, err := timestamp.ParseResponse(raw)
+		if err != nil {
+			rejections.MalformedIndexes = append(rejections.MalformedIndexes, i)
+			continue
+		}
+		parsed[i] = ts
+	}
+
+	keepIndexes := classifyParsedTimestamps(parsed, nonce, rejections)
+
+	kept := make([][]byte, 0, len(keepIndexes))
+	for _, i := range keepIndexes {
+		kept = append(kept, signedTimestamps[i])
+	}
+	return kept, rejections
+}
+
+// classifyParsedTimestamps decides, for each successfully-parsed entry in
+// parsed, whether to keep it or record its index in rejections as a
+// duplicate or nonce mismatch. Indexes whose parsed entry is nil (already
+// rejected as malformed by the caller) are skipped. Returns the indexes to
+// keep, in their original order.
+func classifyParsedTimestamps(parsed []*timestamp.Timestamp, nonce []byte, rejections *TimestampRejectionError) []int {
+	seen := make(map[string]struct{}, len(parsed))
+	keep := make([]int, 0, len(parsed))
+
+	for i, ts := range parsed {
+		if ts == nil {
+			continue
+		}
+
+		// Compare as big.Int values, not raw bytes: big.Int.Bytes() drops
+		// leading zero bytes, so a nonce whose big-endian encoding happens to
+		// start with 0x00 would never byte-compare equal to ts.Nonce.Bytes()
+		// even when the numeric values match.
+		if len(nonce) > 0 && (ts.Nonce == nil || ts.Nonce.Cmp(new(big.Int).SetBytes(nonce)) != 0) {
+			rejections.NonceMismatchIndexes = append(rejections.NonceMismatchIndexes, i)
+			continue
+		}
+
+		key := timestampDedupeKey(ts)
+		if _, ok := seen[key]; ok {
+			rejections.DuplicateIndexes = append(rejections.DuplicateIndexes, i)
+			continue
+		}
+		seen[key] = struct{}{}
+		keep = append(keep, i)
+	}
 
-	if err != nil || (len(signedTimestamps) < p.threshold) {
+	return keep
+}
+
+// timestampDedupeKey hashes the fields of a TSTInfo that together identify
+// a distinct timestamp: serial number, TSA identity (via its signing
+// certificate, since digitorus/timestamp doesn't expose the TSTInfo `tsa`
+// GeneralName directly), genTime, message imprint, and nonce.
+func timestampDedupeKey(ts *timestamp.Timestamp) string {
+	h := sha256.New()
+	if ts.SerialNumber != nil {
+		h.Write(ts.SerialNumber.Bytes())
+	}
+	if len(ts.Certificates) > 0 {
+		h.Write(ts.Certificates[0].Raw)
+	}
+	h.Write([]byte(ts.Time.UTC().Format(time.RFC3339Nano)))
+	h.Write(ts.HashedMessage)
+	if ts.Nonce != nil {
+		h.Write(ts.Nonce.Bytes())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (p *TimestampAuthorityVerifier) NewVerify(entity SignedEntity, opts ...VerifyOption) ([]time.Time, error) {
+	options := &verifyOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	signedTimestamps, err := entity.Timestamps()
+	if err != nil {
 		return nil, fmt.Errorf("not enough signed timestamps: %d < %d", len(signedTimestamps), p.threshold)
 	}
 
+	dedupedTimestamps, rejections := dedupeSignedTimestamps(signedTimestamps, options.nonce)
+	if len(dedupedTimestamps) < p.threshold {
+		if rejections.hasRejections() {
+			return nil, fmt.Errorf("not enough signed timestamps after deduplication: %d < %d: %w", len(dedupedTimestamps), p.threshold, rejections)
+		}
+		return nil, fmt.Errorf("not enough signed timestamps: %d < %d", len(dedupedTimestamps), p.threshold)
+	}
+
 	sigContent, err := entity.SignatureContent()
 	if err != nil {
 		return nil, err
@@ -43,18 +244,68 @@ func (p *TimestampAuthorityVerifier) NewVerify(entity SignedEntity) ([]time.Time
 		return nil, err
 	}
 
-	verifiedTimestamps := []time.Time{}
-	for _, timestamp := range signedTimestamps {
-		verifiedSignedTimestamp, err := verifySignedTimestamp(timestamp, signatureBytes, p.trustedMaterial, verificationContent)
+	acceptableTimes := make([]AcceptableTime, 0, len(dedupedTimestamps))
+	for _, timestamp := range dedupedTimestamps {
+		acceptableTime, err := verifySignedTimestamp(timestamp, signatureBytes, p.trustedMaterial, p.revocationChecker, p.revocationPolicy, p.allowedTSAHashAlgorithms)
 		if err != nil {
+			var revocationErr *RevocationError
+			if errors.As(err, &revocationErr) {
+				return nil, err
+			}
 			return nil, errors.New("unable to verify timestamp")
 		}
-		verifiedTimestamps = append(verifiedTimestamps, verifiedSignedTimestamp)
+		acceptableTimes = append(acceptableTimes, acceptableTime)
+	}
+	acceptableTimes = append(acceptableTimes, options.acceptableTimes...)
+
+	accepted, err := VerifyAcceptableTimes(acceptableTimes, verificationContent, p.trustedMaterial, p.threshold)
+	if err != nil {
+		return nil, err
+	}
+	return verifiedTimes(accepted), nil
+}
+
+// checkChainRevocation consults checker for every certificate in chain
+// (ordered leaf-first) as of at, failing closed or open per policy. It is a
+// no-op if checker is nil, which preserves the pre-revocation-checking
+// behavior for callers that haven't opted in.
+func checkChainRevocation(checker root.RevocationChecker, policy root.RevocationPolicy, chain []*x509.Certificate, at time.Time) error {
+	if checker == nil {
+		return nil
+	}
+
+	for i, cert := range chain {
+		issuer := cert
+		if i+1 < len(chain) {
+			issuer = chain[i+1]
+		}
+
+		status, err := checker.CheckCertificate(cert, issuer, at)
+		if status == root.RevocationRevoked {
+			return fmt.Errorf("certificate %s was revoked as of %s", cert.Subject, at)
+		}
+		if status == root.RevocationUnknown && policy == root.RevocationHardFail {
+			return fmt.Errorf("revocation status of certificate %s is unknown: %w", cert.Subject, err)
+		}
+	}
+	return nil
+}
+
+// tsaHashAlgorithmAllowed reports whether hash is acceptable given the
+// caller's policy. An empty allowed list permits any algorithm.
+func tsaHashAlgorithmAllowed(hash crypto.Hash, allowed []crypto.Hash) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == hash {
+			return true
+		}
 	}
-	return verifiedTimestamps, nil
+	return false
 }
 
-func verifySignedTimestamp(signedTimestamp []byte, dsseSignatureBytes []byte, trustedMaterial root.TrustedMaterial, verificationContent bundle.VerificationContent) (time.Time, error) {
+func verifySignedTimestamp(signedTimestamp []byte, dsseSignatureBytes []byte, trustedMaterial root.TrustedMaterial, revocationChecker root.RevocationChecker, revocationPolicy root.RevocationPolicy, allowedHashAlgorithms []crypto.Hash) (AcceptableTime, error) {
 	certAuthorities := trustedMaterial.TSACertificateAuthorities()
 
 	// Iterate through TSA certificate authorities to find one that verifies
@@ -73,12 +324,18 @@ func verifySignedTimestamp(signedTimestamp []byte, dsseSignatureBytes []byte, tr
 			tsaIntermediateCertPool.AddCert(intermediateCert)
 		}
 
-		// Ensure timestamp responses are from trusted sources
+		// Ensure timestamp responses are from trusted sources. VerifyTimestampResponse
+		// hashes dsseSignatureBytes with whatever algorithm TSTInfo.MessageImprint
+		// names and compares it against HashedMessage, so SHA-256/384/512 all work here.
 		timestamp, err := tsaverification.VerifyTimestampResponse(signedTimestamp, bytes.NewReader(dsseSignatureBytes), trustedRootVerificationOptions)
 		if err != nil {
 			continue
 		}
 
+		if !tsaHashAlgorithmAllowed(timestamp.HashAlgorithm, allowedHashAlgorithms) {
+			continue
+		}
+
 		// Check that the timestamp is valid for the provided certificate
 		verificationOptions := x509.VerifyOptions{
 			CurrentTime:   timestamp.Time,
@@ -100,17 +357,33 @@ func verifySignedTimestamp(signedTimestamp []byte, dsseSignatureBytes []byte, tr
 			continue
 		}
 
-		// Check tlog entry time against bundle certificates
-		// TODO: technically no longer needed since we check the cert validity period in the main Verify loop
-		if !verificationContent.ValidAtTime(timestamp.Time, trustedMaterial) {
-			continue
+		// Check that none of the TSA leaf or intermediate certificates have
+		// been revoked as of the timestamp's GenTime. ca.Root is appended
+		// so the last intermediate (or the leaf, if there are none) resolves
+		// to its true issuer instead of itself.
+		chain := append([]*x509.Certificate{ca.Leaf}, ca.Intermediates...)
+		chain = append(chain, ca.Root)
+		if err := checkChainRevocation(revocationChecker, revocationPolicy, chain, timestamp.Time); err != nil {
+			// Unlike the checks above, this isn't a reason to keep looking
+			// at other configured CAs: the signature and chain already
+			// verified against this one, so a revoked (or, under hard-fail,
+			// unknown) certificate in its chain is a definitive rejection of
+			// this signed timestamp, not ambiguity to resolve by trying
+			// another trust anchor.
+			return AcceptableTime{}, &RevocationError{err: err}
 		}
 
-		// All above verification successful, so return nil
-		return timestamp.Time, nil
+		// All above verification successful; whether this time actually
+		// falls within the signing certificate's validity window is decided
+		// once, across all timestamp sources, by VerifyAcceptableTimes.
+		return AcceptableTime{
+			Source: TSATimeSource,
+			Time:   timestamp.Time,
+			Chain:  chain,
+		}, nil
 	}
 
-	return time.Time{}, errors.New("Unable to verify signed timestamps")
+	return AcceptableTime{}, errors.New("Unable to verify signed timestamps")
 }
 
 func NewTimestampAuthorityVerifier(trustedMaterial root.TrustedMaterial, threshold int) *TimestampAuthorityVerifier {
@@ -0,0 +1,205 @@
+package verifier
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+
+	"github.com/github/sigstore-verifier/pkg/root"
+)
+
+type recordedCheck struct {
+	cert, issuer *x509.Certificate
+}
+
+type recordingChecker struct {
+	calls  []recordedCheck
+	status root.RevocationStatus
+}
+
+func (r *recordingChecker) CheckCertificate(cert, issuer *x509.Certificate, at time.Time) (root.RevocationStatus, error) {
+	r.calls = append(r.calls, recordedCheck{cert, issuer})
+	return r.status, nil
+}
+
+func TestCheckChainRevocationResolvesIssuersAcrossFullChain(t *testing.T) {
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	intermediate := &x509.Certificate{SerialNumber: big.NewInt(2)}
+	rootCert := &x509.Certificate{SerialNumber: big.NewInt(3)}
+	chain := []*x509.Certificate{leaf, intermediate, rootCert}
+
+	checker := &recordingChecker{status: root.RevocationOK}
+	if err := checkChainRevocation(checker, root.RevocationSoftFail, chain, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(checker.calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(checker.calls))
+	}
+	if checker.calls[0].issuer != intermediate {
+		t.Fatalf("leaf's issuer should be the intermediate, got %v", checker.calls[0].issuer)
+	}
+	if checker.calls[1].issuer != rootCert {
+		t.Fatalf("intermediate's issuer should be the root, got %v", checker.calls[1].issuer)
+	}
+	if checker.calls[2].issuer != rootCert {
+		t.Fatalf("root should be its own issuer, got %v", checker.calls[2].issuer)
+	}
+}
+
+func TestCheckChainRevocationLeafWithNoIntermediatesUsesRootAsIssuer(t *testing.T) {
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	rootCert := &x509.Certificate{SerialNumber: big.NewInt(2)}
+	chain := []*x509.Certificate{leaf, rootCert}
+
+	checker := &recordingChecker{status: root.RevocationOK}
+	if err := checkChainRevocation(checker, root.RevocationSoftFail, chain, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checker.calls[0].issuer == leaf {
+		t.Fatal("leaf's issuer must not resolve to itself when a root is present in the chain")
+	}
+	if checker.calls[0].issuer != rootCert {
+		t.Fatalf("leaf's issuer should be the root, got %v", checker.calls[0].issuer)
+	}
+}
+
+func TestCheckChainRevocationFailsOnRevokedCertificate(t *testing.T) {
+	chain := []*x509.Certificate{
+		{SerialNumber: big.NewInt(1)},
+		{SerialNumber: big.NewInt(2)},
+	}
+	checker := &recordingChecker{status: root.RevocationRevoked}
+	if err := checkChainRevocation(checker, root.RevocationSoftFail, chain, time.Now()); err == nil {
+		t.Fatal("expected an error for a revoked certificate")
+	}
+}
+
+func TestCheckChainRevocationUnknownStatusRespectsPolicy(t *testing.T) {
+	chain := []*x509.Certificate{
+		{SerialNumber: big.NewInt(1)},
+		{SerialNumber: big.NewInt(2)},
+	}
+	checker := &recordingChecker{status: root.RevocationUnknown}
+
+	if err := checkChainRevocation(checker, root.RevocationSoftFail, chain, time.Now()); err != nil {
+		t.Fatalf("soft-fail should tolerate an unknown status, got: %v", err)
+	}
+	if err := checkChainRevocation(checker, root.RevocationHardFail, chain, time.Now()); err == nil {
+		t.Fatal("hard-fail should reject an unknown status")
+	}
+}
+
+func TestRevocationErrorWrapsCheckChainRevocationError(t *testing.T) {
+	chain := []*x509.Certificate{
+		{SerialNumber: big.NewInt(1)},
+		{SerialNumber: big.NewInt(2)},
+	}
+	checker := &recordingChecker{status: root.RevocationRevoked}
+	err := checkChainRevocation(checker, root.RevocationSoftFail, chain, time.Now())
+	if err == nil {
+		t.Fatal("expected an error for a revoked certificate")
+	}
+
+	// checkChainRevocation itself returns a plain error; it's
+	// verifySignedTimestamp's job to wrap it as a *RevocationError so
+	// NewVerify can distinguish it from other verification failures. Confirm
+	// that wrapping round-trips through errors.As.
+	wrapped := &RevocationError{err: err}
+	var got *RevocationError
+	if !errors.As(error(wrapped), &got) {
+		t.Fatal("expected errors.As to find the RevocationError")
+	}
+	if !errors.Is(wrapped.Unwrap(), err) {
+		t.Fatal("expected Unwrap to return the original error")
+	}
+}
+
+func TestTsaHashAlgorithmAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    crypto.Hash
+		allowed []crypto.Hash
+		want    bool
+	}{
+		{"empty allowlist permits anything", crypto.SHA1, nil, true},
+		{"SHA-256 permitted", crypto.SHA256, []crypto.Hash{crypto.SHA256, crypto.SHA384}, true},
+		{"SHA-384 permitted", crypto.SHA384, []crypto.Hash{crypto.SHA256, crypto.SHA384}, true},
+		{"SHA-512 permitted", crypto.SHA512, []crypto.Hash{crypto.SHA512}, true},
+		{"SHA-1 rejected when not listed", crypto.SHA1, []crypto.Hash{crypto.SHA256}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tsaHashAlgorithmAllowed(tt.hash, tt.allowed); got != tt.want {
+				t.Errorf("tsaHashAlgorithmAllowed(%v, %v) = %v, want %v", tt.hash, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyParsedTimestampsRejectsDuplicates(t *testing.T) {
+	ts := &timestamp.Timestamp{
+		SerialNumber:  big.NewInt(1),
+		Time:          time.Unix(1700000000, 0),
+		HashedMessage: []byte("digest"),
+	}
+	duplicate := *ts // same TSTInfo contents, distinct pointer
+
+	rejections := &TimestampRejectionError{}
+	kept := classifyParsedTimestamps([]*timestamp.Timestamp{ts, &duplicate}, nil, rejections)
+
+	if len(kept) != 1 || kept[0] != 0 {
+		t.Fatalf("expected only index 0 to be kept, got %v", kept)
+	}
+	if len(rejections.DuplicateIndexes) != 1 || rejections.DuplicateIndexes[0] != 1 {
+		t.Fatalf("expected index 1 to be flagged as a duplicate, got %v", rejections.DuplicateIndexes)
+	}
+}
+
+func TestClassifyParsedTimestampsRejectsNonceMismatch(t *testing.T) {
+	matching := &timestamp.Timestamp{SerialNumber: big.NewInt(1), Nonce: big.NewInt(42)}
+	mismatched := &timestamp.Timestamp{SerialNumber: big.NewInt(2), Nonce: big.NewInt(7)}
+	missing := &timestamp.Timestamp{SerialNumber: big.NewInt(3)}
+
+	rejections := &TimestampRejectionError{}
+	kept := classifyParsedTimestamps([]*timestamp.Timestamp{matching, mismatched, missing}, big.NewInt(42).Bytes(), rejections)
+
+	if len(kept) != 1 || kept[0] != 0 {
+		t.Fatalf("expected only the matching nonce to be kept, got %v", kept)
+	}
+	if len(rejections.NonceMismatchIndexes) != 2 {
+		t.Fatalf("expected 2 nonce mismatches, got %v", rejections.NonceMismatchIndexes)
+	}
+}
+
+func TestClassifyParsedTimestampsNonceMatchesDespiteLeadingZeroByte(t *testing.T) {
+	ts := &timestamp.Timestamp{SerialNumber: big.NewInt(1), Nonce: big.NewInt(42)}
+	// 42's big-endian encoding is a single byte (0x2a); prepending a zero
+	// byte doesn't change its numeric value, but big.Int.Bytes() never
+	// produces a leading zero, so a byte-slice comparison against this
+	// would wrongly reject it.
+	nonceWithLeadingZero := append([]byte{0x00}, big.NewInt(42).Bytes()...)
+
+	rejections := &TimestampRejectionError{}
+	kept := classifyParsedTimestamps([]*timestamp.Timestamp{ts}, nonceWithLeadingZero, rejections)
+
+	if len(kept) != 1 {
+		t.Fatalf("expected the nonce to match despite its leading zero byte, got kept=%v rejections=%+v", kept, rejections)
+	}
+}
+
+func TestDedupeSignedTimestampsRejectsMalformedTokens(t *testing.T) {
+	kept, rejections := dedupeSignedTimestamps([][]byte{[]byte("not a valid RFC 3161 token")}, nil)
+
+	if len(kept) != 0 {
+		t.Fatalf("expected malformed token to be dropped, got %d kept", len(kept))
+	}
+	if len(rejections.MalformedIndexes) != 1 {
+		t.Fatalf("expected 1 malformed index, got %v", rejections.MalformedIndexes)
+	}
+}